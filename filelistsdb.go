@@ -0,0 +1,126 @@
+package yum
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cavaliercoder/go-rpm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Queries to create filelists_db schema
+const (
+	sqlCreateFilelistsTables = `CREATE TABLE db_info (dbversion INTEGER, checksum TEXT);
+CREATE TABLE filelist_ids ( pkgKey INTEGER PRIMARY KEY, pkgId TEXT, name TEXT, epoch TEXT, version TEXT, release TEXT, arch TEXT);
+CREATE TABLE filelist ( pkgKey INTEGER, dirname TEXT, filenames TEXT, filetypes TEXT );`
+
+	sqlCreateFilelistsIndexes = `CREATE INDEX keyfile ON filelist (pkgKey);
+CREATE INDEX pkgId ON filelist_ids (pkgId);`
+
+	sqlInsertFilelistsPackage = `INSERT INTO filelist_ids(name, epoch, version, release, arch, pkgId) VALUES (?, ?, ?, ?, ?, ?);`
+
+	sqlInsertFilelistsFiles = `INSERT INTO filelist(pkgKey, dirname, filenames, filetypes) VALUES (?, ?, ?, ?);`
+)
+
+// FilelistsDatabase is an SQLite database which contains the file listing
+// for every package in a yum package repository.
+type FilelistsDatabase struct {
+	db     *sql.DB
+	dbpath string
+}
+
+// CreateFilelistsDB initializes a new and empty filelists_db SQLite
+// database on disk. Any existing path is deleted.
+func CreateFilelistsDB(path string) (*FilelistsDatabase, error) {
+	os.Remove(path)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating Filelists DB: %v", err)
+	}
+
+	if _, err = db.Exec(sqlCreateFilelistsTables); err != nil {
+		return nil, fmt.Errorf("Error creating Filelists DB tables: %v", err)
+	}
+
+	if _, err = db.Exec(sqlCreateFilelistsIndexes); err != nil {
+		return nil, fmt.Errorf("Error creating Filelists DB indexes: %v", err)
+	}
+
+	return &FilelistsDatabase{
+		db:     db,
+		dbpath: path,
+	}, nil
+}
+
+func (c *FilelistsDatabase) Close() error {
+	if c.db != nil {
+		return c.db.Close()
+	}
+
+	return nil
+}
+
+// InsertPackage records the file listing of p, grouping files by their
+// containing directory the same way createrepo's filelists.sqlite does.
+func (c *FilelistsDatabase) InsertPackage(p *rpm.PackageFile) error {
+	sum, err := p.Checksum()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.Exec(sqlInsertFilelistsPackage, p.Name(), p.Epoch(), p.Version(), p.Release(), p.Architecture(), sum)
+	if err != nil {
+		return err
+	}
+
+	pkgKey, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := c.db.Prepare(sqlInsertFilelistsFiles)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for dirname, names := range groupFilesByDirectory(p.Files()) {
+		filetypes := strings.Repeat("f", len(names))
+		if _, err := stmt.Exec(pkgKey, dirname, strings.Join(names, "/"), filetypes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupFilesByDirectory buckets a flat list of absolute file paths by their
+// containing directory, matching the dirname/filenames layout used by
+// createrepo's filelists.sqlite.
+func groupFilesByDirectory(files []string) map[string][]string {
+	dirs := make(map[string][]string)
+
+	for _, f := range files {
+		dir := "/"
+		name := f
+
+		if i := strings.LastIndex(f, "/"); i >= 0 {
+			dir = f[:i]
+			if dir == "" {
+				dir = "/"
+			}
+			name = f[i+1:]
+		}
+
+		dirs[dir] = append(dirs[dir], name)
+	}
+
+	for _, names := range dirs {
+		sort.Strings(names)
+	}
+
+	return dirs
+}
@@ -0,0 +1,76 @@
+package yum
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// FilelistsMetadata represents the filelists.xml metadata file for a
+// RPM/Yum repository. It lists every file shipped by each package in the
+// repository.
+type FilelistsMetadata struct {
+	XMLName       xml.Name `xml:"filelists"`
+	XMLNS         string   `xml:"xmlns,attr"`
+	PackagesCount int      `xml:"packages,attr"`
+
+	Packages []FilelistsPackage `xml:"package"`
+}
+
+// FilelistsPackage is a single <package> entry in filelists.xml.
+type FilelistsPackage struct {
+	PkgId   string           `xml:"pkgid,attr"`
+	Name    string           `xml:"name,attr"`
+	Arch    string           `xml:"arch,attr"`
+	Version FilelistsVersion `xml:"version"`
+	Files   []FilelistsFile  `xml:"file"`
+}
+
+// FilelistsVersion describes the epoch/version/release of a package in
+// filelists.xml.
+type FilelistsVersion struct {
+	Epoch   int    `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+// FilelistsFile is a single file or directory shipped by a package. Type is
+// "dir" or "ghost" for special entries, and omitted for regular files.
+type FilelistsFile struct {
+	Type string `xml:"type,attr,omitempty"`
+	Path string `xml:",chardata"`
+}
+
+// ReadFilelistsMetadata loads a filelists.xml file from the given
+// io.Reader and returns a pointer to the resulting FilelistsMetadata
+// struct.
+func ReadFilelistsMetadata(r io.Reader) (*FilelistsMetadata, error) {
+	md := FilelistsMetadata{
+		Packages: make([]FilelistsPackage, 0),
+	}
+
+	decoder := xml.NewDecoder(r)
+	if err := decoder.Decode(&md); err != nil {
+		return nil, fmt.Errorf("Error decoding filelists metadata: %v", err)
+	}
+
+	return &md, nil
+}
+
+// WriteFilelistsMetadata encodes md as filelists.xml to the given
+// io.Writer.
+func WriteFilelistsMetadata(w io.Writer, md *FilelistsMetadata) error {
+	md.XMLNS = "http://linux.duke.edu/metadata/filelists"
+	md.PackagesCount = len(md.Packages)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if err := encoder.Encode(md); err != nil {
+		return fmt.Errorf("Error encoding filelists metadata: %v", err)
+	}
+
+	return encoder.Flush()
+}
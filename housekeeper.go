@@ -0,0 +1,188 @@
+package yum
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cavaliercoder/go-rpm"
+)
+
+// ReconcileOptions configures a call to PrimaryDatabase.Reconcile.
+type ReconcileOptions struct {
+	// QuarantineDir, if set, causes orphaned RPM files to be moved there
+	// instead of being deleted outright.
+	QuarantineDir string
+
+	// Reindex causes RPMs found on disk with no matching row in the
+	// database to be inserted via InsertPackage.
+	Reindex bool
+}
+
+// ReconcileReport enumerates the outcome of a call to
+// PrimaryDatabase.Reconcile, so operators running it as a cron job can log
+// or alert on what changed.
+type ReconcileReport struct {
+	// Orphans lists RPM files found on disk with no matching pkgKey,
+	// that were deleted or quarantined.
+	Orphans []string
+
+	// Missing lists location_href values whose packages row was
+	// deleted because the file no longer exists on disk.
+	Missing []string
+
+	// Mismatched lists location_href values whose file was re-hashed
+	// and no longer matches the stored pkgId, suggesting the file was
+	// corrupted or replaced in place.
+	Mismatched []string
+
+	// Added lists RPM files found on disk but missing from the
+	// database, that were re-indexed.
+	Added []string
+}
+
+// Housekeeper reconciles a repository directory of RPM files against a
+// PrimaryDatabase, analogous to ALHP.GO's housekeeping pass.
+type Housekeeper struct {
+	db      *PrimaryDatabase
+	repoDir string
+	opts    ReconcileOptions
+}
+
+// Reconcile walks repoDir for *.rpm files and reconciles them against c:
+// database rows whose file no longer exists on disk are deleted, present
+// files are re-hashed to detect corruption or silent replacement, and
+// files found on disk with no matching database row are either deleted
+// (or quarantined) or, when opts.Reindex is set, re-indexed instead.
+func (c *PrimaryDatabase) Reconcile(repoDir string, opts ReconcileOptions) (*ReconcileReport, error) {
+	h := &Housekeeper{db: c, repoDir: repoDir, opts: opts}
+	return h.run()
+}
+
+func (h *Housekeeper) run() (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+
+	diskFiles, err := h.walkRPMs()
+	if err != nil {
+		return nil, fmt.Errorf("Error walking %s: %v", h.repoDir, err)
+	}
+
+	packages, err := h.db.Packages()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing packages: %v", err)
+	}
+
+	known := make(map[string]PackageEntry, len(packages))
+	for _, p := range packages {
+		known[p.Location.Href] = p
+	}
+
+	// (a) orphan files on disk with no matching database row. When
+	// opts.Reindex is set, these same files are handled by pass (d)
+	// instead, so they must not be pruned here first.
+	if !h.opts.Reindex {
+		for href, path := range diskFiles {
+			if _, ok := known[href]; ok {
+				continue
+			}
+
+			if err := h.quarantineOrRemove(href, path); err != nil {
+				return nil, err
+			}
+
+			report.Orphans = append(report.Orphans, href)
+		}
+	}
+
+	// (b) and (c): database rows whose file is missing or corrupted
+	for href, p := range known {
+		path, present := diskFiles[href]
+		if !present {
+			if err := h.deletePackage(p.Key); err != nil {
+				return nil, err
+			}
+
+			report.Missing = append(report.Missing, href)
+			continue
+		}
+
+		pkg, err := rpm.OpenPackageFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error re-hashing %s: %v", path, err)
+		}
+
+		sum, err := pkg.Checksum()
+		if err != nil {
+			return nil, fmt.Errorf("Error re-hashing %s: %v", path, err)
+		}
+
+		wantSum, err := p.Checksum()
+		if err != nil {
+			return nil, err
+		}
+
+		if sum != wantSum {
+			report.Mismatched = append(report.Mismatched, href)
+		}
+	}
+
+	// (d) files found on disk but missing from the database
+	if h.opts.Reindex {
+		for href, path := range diskFiles {
+			if _, ok := known[href]; ok {
+				continue
+			}
+
+			pkg, err := rpm.OpenPackageFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("Error opening %s: %v", path, err)
+			}
+
+			if err := h.db.InsertPackage(pkg); err != nil {
+				return nil, fmt.Errorf("Error re-indexing %s: %v", path, err)
+			}
+
+			report.Added = append(report.Added, href)
+		}
+	}
+
+	return report, nil
+}
+
+// walkRPMs returns every *.rpm file under repoDir, keyed by its basename
+// (the same value stored as location_href).
+func (h *Housekeeper) walkRPMs() (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := filepath.Walk(h.repoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || filepath.Ext(path) != ".rpm" {
+			return nil
+		}
+
+		files[filepath.Base(path)] = path
+		return nil
+	})
+
+	return files, err
+}
+
+func (h *Housekeeper) quarantineOrRemove(href, path string) error {
+	if h.opts.QuarantineDir == "" {
+		return os.Remove(path)
+	}
+
+	if err := os.MkdirAll(h.opts.QuarantineDir, 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(path, filepath.Join(h.opts.QuarantineDir, href))
+}
+
+func (h *Housekeeper) deletePackage(pkgKey int) error {
+	_, err := h.db.db.Exec("DELETE FROM packages WHERE pkgKey = ?", pkgKey)
+	return err
+}
@@ -0,0 +1,153 @@
+package yum
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/cavaliercoder/go-rpm"
+)
+
+// IndexOptions configures a call to PrimaryDatabase.InsertPackagesParallel.
+type IndexOptions struct {
+	// MemoryLimit bounds how many parsed RPM headers may be buffered
+	// in flight between the parser workers and the DB writer, to keep
+	// indexing large trees of packages from exhausting memory. A zero
+	// value disables the limit.
+	MemoryLimit datasize.ByteSize
+
+	// OnProgress, if set, is invoked after each package has been parsed
+	// and written, reporting how many of the total paths have been
+	// processed so far.
+	OnProgress func(done, total int)
+}
+
+// averageHeaderSize estimates the in-memory footprint of a single parsed
+// RPM header, used to translate opts.MemoryLimit into a channel buffer
+// size.
+const averageHeaderSize = 64 * datasize.KB
+
+// indexResult is a package parsed by a worker, ready to be written to the
+// database by the single writer goroutine.
+type indexResult struct {
+	path string
+	pkg  *rpm.PackageFile
+	err  error
+}
+
+// InsertPackagesParallel indexes the RPM files at paths into the database
+// using runtime.NumCPU() worker goroutines to parse RPM headers and
+// checksums concurrently, while a single goroutine writes the results to
+// the database inside one transaction. Workers do not guarantee any
+// ordering. If ctx is canceled, indexing stops and the transaction is
+// rolled back.
+func (c *PrimaryDatabase) InsertPackagesParallel(ctx context.Context, paths []string, opts IndexOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	bufSize := len(paths)
+	if opts.MemoryLimit > 0 {
+		if n := int(opts.MemoryLimit / averageHeaderSize); n > 0 && n < bufSize {
+			bufSize = n
+		}
+	}
+	if bufSize < 1 {
+		bufSize = 1
+	}
+
+	jobs := make(chan string)
+	results := make(chan indexResult, bufSize)
+
+	var wg sync.WaitGroup
+	workers := runtime.NumCPU()
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for path := range jobs {
+				pkg, err := rpm.OpenPackageFile(path)
+				select {
+				case results <- indexResult{path: path, pkg: pkg, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for _, path := range paths {
+			select {
+			case jobs <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return c.writeIndexResults(ctx, results, len(paths), opts.OnProgress)
+}
+
+// writeIndexResults consumes parsed packages from results and writes them,
+// including their full dependency graph, to the database inside a single
+// transaction, aborting and rolling back if ctx is canceled.
+func (c *PrimaryDatabase) writeIndexResults(ctx context.Context, results <-chan indexResult, total int, onProgress func(done, total int)) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmts, err := preparePackageStmts(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmts.Close()
+
+	done := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			tx.Rollback()
+			return ctx.Err()
+
+		case res, ok := <-results:
+			if !ok {
+				if err := tx.Commit(); err != nil {
+					return fmt.Errorf("Error committing parallel index transaction: %v", err)
+				}
+				return nil
+			}
+
+			if res.err != nil {
+				tx.Rollback()
+				return fmt.Errorf("Error parsing %s: %v", res.path, res.err)
+			}
+
+			if err := insertPackageWithStmts(stmts, res.pkg); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			done++
+			if onProgress != nil {
+				onProgress(done, total)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package yum
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/cavaliercoder/go-rpm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Queries to create other_db schema
+const (
+	sqlCreateOtherTables = `CREATE TABLE db_info (dbversion INTEGER, checksum TEXT);
+CREATE TABLE packages ( pkgKey INTEGER PRIMARY KEY, pkgId TEXT, name TEXT, epoch TEXT, version TEXT, release TEXT, arch TEXT);
+CREATE TABLE changelog ( pkgKey INTEGER, author TEXT, date INTEGER, changelog TEXT);`
+
+	sqlCreateOtherIndexes = `CREATE INDEX keychangelog ON changelog (pkgKey);
+CREATE INDEX packageId ON packages (pkgId);`
+
+	sqlInsertOtherPackage = `INSERT INTO packages(name, epoch, version, release, arch, pkgId) VALUES (?, ?, ?, ?, ?, ?);`
+
+	sqlInsertOtherChangelog = `INSERT INTO changelog(pkgKey, author, date, changelog) VALUES (?, ?, ?, ?);`
+)
+
+// OtherDatabase is an SQLite database which contains the changelog history
+// for every package in a yum package repository.
+type OtherDatabase struct {
+	db     *sql.DB
+	dbpath string
+}
+
+// CreateOtherDB initializes a new and empty other_db SQLite database on
+// disk. Any existing path is deleted.
+func CreateOtherDB(path string) (*OtherDatabase, error) {
+	os.Remove(path)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating Other DB: %v", err)
+	}
+
+	if _, err = db.Exec(sqlCreateOtherTables); err != nil {
+		return nil, fmt.Errorf("Error creating Other DB tables: %v", err)
+	}
+
+	if _, err = db.Exec(sqlCreateOtherIndexes); err != nil {
+		return nil, fmt.Errorf("Error creating Other DB indexes: %v", err)
+	}
+
+	return &OtherDatabase{
+		db:     db,
+		dbpath: path,
+	}, nil
+}
+
+func (c *OtherDatabase) Close() error {
+	if c.db != nil {
+		return c.db.Close()
+	}
+
+	return nil
+}
+
+// InsertPackage records the changelog history of p.
+func (c *OtherDatabase) InsertPackage(p *rpm.PackageFile) error {
+	sum, err := p.Checksum()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.Exec(sqlInsertOtherPackage, p.Name(), p.Epoch(), p.Version(), p.Release(), p.Architecture(), sum)
+	if err != nil {
+		return err
+	}
+
+	pkgKey, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := c.db.Prepare(sqlInsertOtherChangelog)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, entry := range p.Changelogs() {
+		if _, err := stmt.Exec(pkgKey, entry.Name, entry.Time, entry.Text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
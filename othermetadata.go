@@ -0,0 +1,74 @@
+package yum
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// OtherMetadata represents the other.xml metadata file for a RPM/Yum
+// repository. It lists the changelog history of each package in the
+// repository.
+type OtherMetadata struct {
+	XMLName       xml.Name `xml:"otherdata"`
+	XMLNS         string   `xml:"xmlns,attr"`
+	PackagesCount int      `xml:"packages,attr"`
+
+	Packages []OtherPackage `xml:"package"`
+}
+
+// OtherPackage is a single <package> entry in other.xml.
+type OtherPackage struct {
+	PkgId      string           `xml:"pkgid,attr"`
+	Name       string           `xml:"name,attr"`
+	Arch       string           `xml:"arch,attr"`
+	Version    OtherVersion     `xml:"version"`
+	Changelogs []OtherChangelog `xml:"changelog"`
+}
+
+// OtherVersion describes the epoch/version/release of a package in
+// other.xml.
+type OtherVersion struct {
+	Epoch   int    `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+// OtherChangelog is a single changelog entry for a package.
+type OtherChangelog struct {
+	Author string `xml:"author,attr"`
+	Date   int64  `xml:"date,attr"`
+	Text   string `xml:",chardata"`
+}
+
+// ReadOtherMetadata loads an other.xml file from the given io.Reader and
+// returns a pointer to the resulting OtherMetadata struct.
+func ReadOtherMetadata(r io.Reader) (*OtherMetadata, error) {
+	md := OtherMetadata{
+		Packages: make([]OtherPackage, 0),
+	}
+
+	decoder := xml.NewDecoder(r)
+	if err := decoder.Decode(&md); err != nil {
+		return nil, fmt.Errorf("Error decoding other metadata: %v", err)
+	}
+
+	return &md, nil
+}
+
+// WriteOtherMetadata encodes md as other.xml to the given io.Writer.
+func WriteOtherMetadata(w io.Writer, md *OtherMetadata) error {
+	md.XMLNS = "http://linux.duke.edu/metadata/other"
+	md.PackagesCount = len(md.Packages)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if err := encoder.Encode(md); err != nil {
+		return fmt.Errorf("Error encoding other metadata: %v", err)
+	}
+
+	return encoder.Flush()
+}
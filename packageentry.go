@@ -9,7 +9,7 @@ import (
 type PackageEntry struct {
 	db *PrimaryDatabase
 
-	Key         int
+	Key         int                  `xml:"-"`
 	Arch        string               `xml:"arch"`
 	Size        PackageEntrySize     `xml:"size"`
 	Checksums   PackageEntryChecksum `xml:"checksum"`
@@ -23,7 +23,7 @@ type PackageEntry struct {
 }
 
 type PackageEntrySize struct {
-	Package   int64 `xml:"type,attr"`
+	Package   int64 `xml:"package,attr"`
 	Installed int64 `xml:"installed,attr"`
 	Archive   int64 `xml:"archive,attr"`
 }
@@ -11,8 +11,6 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// TODO: Add support for XML primary dbs
-
 // Queries to create primary_db schema
 const (
 	sqlCreateTables = `CREATE TABLE db_info (dbversion INTEGER, checksum TEXT);
@@ -82,8 +80,49 @@ const (
 ) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 
 	sqlInsertPackageFiles = `INSERT INTO files(name, type, pkgKey) VALUES (?, ?, ?);`
+
+	sqlInsertRequires  = `INSERT INTO requires(name, flags, epoch, version, release, pkgKey, pre) VALUES (?, ?, ?, ?, ?, ?, ?);`
+	sqlInsertProvides  = `INSERT INTO provides(name, flags, epoch, version, release, pkgKey) VALUES (?, ?, ?, ?, ?, ?);`
+	sqlInsertConflicts = `INSERT INTO conflicts(name, flags, epoch, version, release, pkgKey) VALUES (?, ?, ?, ?, ?, ?);`
+	sqlInsertObsoletes = `INSERT INTO obsoletes(name, flags, epoch, version, release, pkgKey) VALUES (?, ?, ?, ?, ?, ?);`
 )
 
+// depFlagString translates a go-rpm rpm.DepFlag* comparison flag back to
+// the string representation used in the requires/provides/conflicts/
+// obsoletes tables ("EQ", "LT", "LE", "GE", "GT"), mirroring the reverse
+// translation in DependenciesByPackage.
+func depFlagString(flags int) string {
+	switch flags & (rpm.DepFlagLesser | rpm.DepFlagGreater | rpm.DepFlagEqual) {
+	case rpm.DepFlagEqual:
+		return "EQ"
+
+	case rpm.DepFlagLesser:
+		return "LT"
+
+	case rpm.DepFlagLesserOrEqual:
+		return "LE"
+
+	case rpm.DepFlagGreaterOrEqual:
+		return "GE"
+
+	case rpm.DepFlagGreater:
+		return "GT"
+	}
+
+	return ""
+}
+
+// nullableEpoch returns epoch as a nullable string, so that an unset (zero)
+// dependency epoch is stored as NULL rather than "0"; DependenciesByPackage
+// reads a NULL epoch back as zero.
+func nullableEpoch(epoch int) sql.NullString {
+	if epoch == 0 {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: fmt.Sprintf("%d", epoch), Valid: true}
+}
+
 // PrimaryDatabase is an SQLite database which contains package data for a
 // yum package repository.
 type PrimaryDatabase struct {
@@ -154,69 +193,149 @@ func (c *PrimaryDatabase) Close() error {
 	return nil
 }
 
-func (c *PrimaryDatabase) InsertPackage(packages ...*rpm.PackageFile) error {
-	// insert package
-	stmt, err := c.db.Prepare(sqlInsertPackage)
+// preparer is satisfied by both *sql.DB and *sql.Tx, letting
+// preparePackageStmts be used for a one-shot insert or inside a
+// transaction.
+type preparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// packageStmts holds the prepared statements needed to insert a package
+// and its full dependency graph.
+type packageStmts struct {
+	pkg       *sql.Stmt
+	files     *sql.Stmt
+	requires  *sql.Stmt
+	provides  *sql.Stmt
+	conflicts *sql.Stmt
+	obsoletes *sql.Stmt
+}
+
+func preparePackageStmts(p preparer) (*packageStmts, error) {
+	var s packageStmts
+	var err error
+
+	if s.pkg, err = p.Prepare(sqlInsertPackage); err != nil {
+		return nil, err
+	}
+	if s.files, err = p.Prepare(sqlInsertPackageFiles); err != nil {
+		return nil, err
+	}
+	if s.requires, err = p.Prepare(sqlInsertRequires); err != nil {
+		return nil, err
+	}
+	if s.provides, err = p.Prepare(sqlInsertProvides); err != nil {
+		return nil, err
+	}
+	if s.conflicts, err = p.Prepare(sqlInsertConflicts); err != nil {
+		return nil, err
+	}
+	if s.obsoletes, err = p.Prepare(sqlInsertObsoletes); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *packageStmts) Close() {
+	s.pkg.Close()
+	s.files.Close()
+	s.requires.Close()
+	s.provides.Close()
+	s.conflicts.Close()
+	s.obsoletes.Close()
+}
+
+// insertPackageWithStmts inserts a package, its files and its full
+// dependency graph (requires, provides, conflicts, obsoletes) using
+// already-prepared statements, shared by InsertPackage and
+// InsertPackagesParallel.
+func insertPackageWithStmts(s *packageStmts, p *rpm.PackageFile) error {
+	sum, err := p.Checksum()
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	href := filepath.Base(p.Path())
+	res, err := s.pkg.Exec(
+		p.Name(),
+		p.Architecture(),
+		p.Epoch(),
+		p.Version(),
+		p.Release(),
+		p.Summary(),
+		p.Description(),
+		p.URL(),
+		p.FileTime().Unix(),
+		p.FileSize(),
+		p.Size(),
+		p.ArchiveSize(),
+		href,
+		sum,
+		p.ChecksumType(),
+		p.BuildTime().Unix(),
+		p.License(),
+		p.Vendor(),
+		strings.Join(p.Groups(), "\n"),
+		p.BuildHost(),
+		p.SourceRPM(),
+		p.HeaderStart(),
+		p.HeaderEnd(),
+		p.Packager())
 
-	// insert files
-	stmtFiles, err := c.db.Prepare(sqlInsertPackageFiles)
 	if err != nil {
 		return err
 	}
 
-	defer stmtFiles.Close()
+	pkgKey, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
 
-	for _, p := range packages {
-		sum, err := p.Checksum()
-		if err != nil {
+	for _, f := range p.Files() {
+		if _, err := s.files.Exec(f, "file", pkgKey); err != nil {
 			return err
 		}
+	}
 
-		href := filepath.Base(p.Path())
-		res, err := stmt.Exec(
-			p.Name(),
-			p.Architecture(),
-			p.Epoch(),
-			p.Version(),
-			p.Release(),
-			p.Summary(),
-			p.Description(),
-			p.URL(),
-			p.FileTime().Unix(),
-			p.FileSize(),
-			p.Size(),
-			p.ArchiveSize(),
-			href,
-			sum,
-			p.ChecksumType(),
-			p.BuildTime().Unix(),
-			p.License(),
-			p.Vendor(),
-			strings.Join(p.Groups(), "\n"),
-			p.BuildHost(),
-			p.SourceRPM(),
-			p.HeaderStart(),
-			p.HeaderEnd(),
-			p.Packager())
-
-		if err != nil {
+	for _, dep := range p.Requires() {
+		pre := dep.Flags()&rpm.DepFlagPreReq != 0
+		if _, err := s.requires.Exec(dep.Name(), depFlagString(dep.Flags()), nullableEpoch(dep.Epoch()), dep.Version(), dep.Release(), pkgKey, pre); err != nil {
 			return err
 		}
+	}
 
-		i, err := res.LastInsertId()
-		if err != nil {
+	for _, dep := range p.Provides() {
+		if _, err := s.provides.Exec(dep.Name(), depFlagString(dep.Flags()), nullableEpoch(dep.Epoch()), dep.Version(), dep.Release(), pkgKey); err != nil {
 			return err
 		}
+	}
 
-		// insert files
-		files := p.Files()
-		for _, f := range files {
-			stmtFiles.Exec(f, "file", i)
+	for _, dep := range p.Conflicts() {
+		if _, err := s.conflicts.Exec(dep.Name(), depFlagString(dep.Flags()), nullableEpoch(dep.Epoch()), dep.Version(), dep.Release(), pkgKey); err != nil {
+			return err
+		}
+	}
+
+	for _, dep := range p.Obsoletes() {
+		if _, err := s.obsoletes.Exec(dep.Name(), depFlagString(dep.Flags()), nullableEpoch(dep.Epoch()), dep.Version(), dep.Release(), pkgKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *PrimaryDatabase) InsertPackage(packages ...*rpm.PackageFile) error {
+	stmts, err := preparePackageStmts(c.db)
+	if err != nil {
+		return err
+	}
+	defer stmts.Close()
+
+	for _, p := range packages {
+		if err := insertPackageWithStmts(stmts, p); err != nil {
+			return err
 		}
 	}
 
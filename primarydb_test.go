@@ -0,0 +1,157 @@
+package yum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cavaliercoder/go-rpm"
+)
+
+// testRPMPath points at a real RPM used to exercise InsertPackage against a
+// live package. It is not checked into the repository; drop a package
+// there to run this test locally.
+const testRPMPath = "testdata/test-1.0-1.noarch.rpm"
+
+// TestDepFlagString exercises the requires/provides/conflicts/obsoletes
+// flag translation directly, without needing a parsed RPM: dep.Flags()
+// returns the raw RPMSENSE_* bits, which routinely carry sense bits other
+// than the version comparison (e.g. RPMSENSE_PREREQ, RPMSENSE_RPMLIB) that
+// must not prevent the comparison from being recognized.
+func TestDepFlagString(t *testing.T) {
+	cases := []struct {
+		name  string
+		flags int
+		want  string
+	}{
+		{"plain EQ", rpm.DepFlagEqual, "EQ"},
+		{"plain LT", rpm.DepFlagLesser, "LT"},
+		{"plain LE", rpm.DepFlagLesserOrEqual, "LE"},
+		{"plain GE", rpm.DepFlagGreaterOrEqual, "GE"},
+		{"plain GT", rpm.DepFlagGreater, "GT"},
+		{"EQ with PreReq", rpm.DepFlagEqual | rpm.DepFlagPreReq, "EQ"},
+		{"GE with PreReq (rpmlib/scriptlet require)", rpm.DepFlagGreaterOrEqual | rpm.DepFlagPreReq, "GE"},
+		{"no comparison bits", rpm.DepFlagPreReq, ""},
+	}
+
+	for _, c := range cases {
+		if got := depFlagString(c.flags); got != c.want {
+			t.Errorf("%s: depFlagString(%#x) = %q, want %q", c.name, c.flags, got, c.want)
+		}
+	}
+}
+
+// TestNullableEpoch confirms a zero (unset) epoch is stored as NULL, not
+// the string "0", which DependenciesByPackage reads back as zero.
+func TestNullableEpoch(t *testing.T) {
+	if got := nullableEpoch(0); got.Valid {
+		t.Errorf("nullableEpoch(0) = %+v, want invalid/NULL", got)
+	}
+
+	got := nullableEpoch(2)
+	if !got.Valid || got.String != "2" {
+		t.Errorf("nullableEpoch(2) = %+v, want {String: \"2\", Valid: true}", got)
+	}
+}
+
+// TestInsertPackageDependencies is a round-trip test: it inserts a real RPM
+// into a fresh primary_db and confirms every dependency class (requires,
+// provides, conflicts, obsoletes) can be read back via
+// DependenciesByPackage with matching flags, epoch and version, and that
+// the "pre" bit on requires is persisted correctly.
+func TestInsertPackageDependencies(t *testing.T) {
+	if _, err := os.Stat(testRPMPath); err != nil {
+		t.Skipf("no test fixture RPM at %s: %v", testRPMPath, err)
+	}
+
+	p, err := rpm.OpenPackageFile(testRPMPath)
+	if err != nil {
+		t.Fatalf("Error opening test RPM: %v", err)
+	}
+
+	dbpath := filepath.Join(t.TempDir(), "primary.sqlite")
+	db, err := CreatePrimaryDB(dbpath)
+	if err != nil {
+		t.Fatalf("Error creating primary DB: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.InsertPackage(p); err != nil {
+		t.Fatalf("Error inserting package: %v", err)
+	}
+
+	packages, err := db.Packages()
+	if err != nil {
+		t.Fatalf("Error listing packages: %v", err)
+	}
+	if len(packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(packages))
+	}
+
+	pkgKey := packages[0].Key
+
+	for typ, want := range map[string]rpm.Dependencies{
+		"requires":  p.Requires(),
+		"provides":  p.Provides(),
+		"conflicts": p.Conflicts(),
+		"obsoletes": p.Obsoletes(),
+	} {
+		got, err := db.DependenciesByPackage(pkgKey, typ)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", typ, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %d dependencies, got %d", typ, len(want), len(got))
+		}
+
+		for i := range want {
+			wantFlags := want[i].Flags() & (rpm.DepFlagLesser | rpm.DepFlagGreater | rpm.DepFlagEqual)
+
+			if got[i].Name() != want[i].Name() {
+				t.Errorf("%s[%d]: name = %q, want %q", typ, i, got[i].Name(), want[i].Name())
+			}
+			if got[i].Flags() != wantFlags {
+				t.Errorf("%s[%d]: flags = %#x, want %#x", typ, i, got[i].Flags(), wantFlags)
+			}
+			if got[i].Epoch() != want[i].Epoch() {
+				t.Errorf("%s[%d]: epoch = %d, want %d", typ, i, got[i].Epoch(), want[i].Epoch())
+			}
+			if got[i].Version() != want[i].Version() {
+				t.Errorf("%s[%d]: version = %q, want %q", typ, i, got[i].Version(), want[i].Version())
+			}
+		}
+	}
+
+	// DependenciesByPackage doesn't surface the "pre" column, so check it
+	// directly against what InsertPackage should have persisted for each
+	// requires row.
+	rows, err := db.db.Query("SELECT name, pre FROM requires WHERE pkgKey = ?", pkgKey)
+	if err != nil {
+		t.Fatalf("Error querying requires.pre: %v", err)
+	}
+	defer rows.Close()
+
+	wantPre := make(map[string]bool, len(p.Requires()))
+	for _, dep := range p.Requires() {
+		wantPre[dep.Name()] = dep.Flags()&rpm.DepFlagPreReq != 0
+	}
+
+	seen := 0
+	for rows.Next() {
+		var name string
+		var pre bool
+		if err := rows.Scan(&name, &pre); err != nil {
+			t.Fatalf("Error scanning requires.pre: %v", err)
+		}
+
+		if want, ok := wantPre[name]; ok && want != pre {
+			t.Errorf("requires[%s]: pre = %v, want %v", name, pre, want)
+		}
+		seen++
+	}
+
+	if seen != len(p.Requires()) {
+		t.Fatalf("expected %d requires rows, saw %d", len(p.Requires()), seen)
+	}
+}
@@ -32,3 +32,20 @@ func ReadPrimaryMetadata(r io.Reader) (*PrimaryMetadata, error) {
 
 	return &md, nil
 }
+
+// WritePrimaryMetadata encodes md as primary.xml to the given io.Writer.
+func WritePrimaryMetadata(w io.Writer, md *PrimaryMetadata) error {
+	md.XMLNS = "http://linux.duke.edu/metadata/common"
+	md.PackagesCount = len(md.Packages)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if err := encoder.Encode(md); err != nil {
+		return fmt.Errorf("Error encoding primary metadata: %v", err)
+	}
+
+	return encoder.Flush()
+}
@@ -0,0 +1,166 @@
+package yum
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+const primaryXMLNS = `xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm"`
+
+// packagesCountWidth is the fixed width reserved for the packages="N"
+// attribute value while streaming to a seekable writer, so it can be
+// patched in place once the final count is known.
+const packagesCountWidth = 10
+
+// PrimaryMetadataWriter streams primary.xml to an io.Writer one package at
+// a time, so large repositories can be built without holding every
+// PackageEntry in memory at once.
+//
+// If w also implements io.Seeker, the writer reserves space for the
+// packages="N" header attribute and seeks back to patch it once Close is
+// called. Otherwise, package bodies are spooled to a temporary file and
+// the header and body are written to w together on Close.
+type PrimaryMetadataWriter struct {
+	w      io.Writer
+	seeker io.Seeker
+	closer io.Closer
+
+	dest    io.Writer
+	spool   *os.File
+	encoder *xml.Encoder
+
+	headerOffset int64
+	count        int
+}
+
+// NewPrimaryMetadataWriter returns a PrimaryMetadataWriter that writes
+// primary.xml to w.
+func NewPrimaryMetadataWriter(w io.Writer) (*PrimaryMetadataWriter, error) {
+	return newPrimaryMetadataWriter(w, nil)
+}
+
+// NewGzippedPrimaryWriter returns a PrimaryMetadataWriter that writes
+// gzip-compressed primary.xml.gz to w.
+func NewGzippedPrimaryWriter(w io.Writer) (*PrimaryMetadataWriter, error) {
+	gz := gzip.NewWriter(w)
+	return newPrimaryMetadataWriter(gz, gz)
+}
+
+func newPrimaryMetadataWriter(w io.Writer, closer io.Closer) (*PrimaryMetadataWriter, error) {
+	c := &PrimaryMetadataWriter{w: w, closer: closer}
+
+	if seeker, ok := w.(io.Seeker); ok {
+		c.seeker = seeker
+	}
+
+	if err := c.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *PrimaryMetadataWriter) writeHeader() error {
+	if c.seeker != nil {
+		prefix := xml.Header + fmt.Sprintf(`<metadata %s packages="`, primaryXMLNS)
+		if _, err := io.WriteString(c.w, prefix); err != nil {
+			return err
+		}
+
+		offset, err := c.seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		c.headerOffset = offset
+
+		if _, err := fmt.Fprintf(c.w, "%0*d", packagesCountWidth, 0); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(c.w, "\">\n"); err != nil {
+			return err
+		}
+
+		c.dest = c.w
+		c.encoder = xml.NewEncoder(c.dest)
+		return nil
+	}
+
+	spool, err := os.CreateTemp("", "primary-*.xml")
+	if err != nil {
+		return fmt.Errorf("Error creating primary.xml spool file: %v", err)
+	}
+
+	c.spool = spool
+	c.dest = spool
+	c.encoder = xml.NewEncoder(c.dest)
+	return nil
+}
+
+// Write encodes a single <package> entry and streams it to the underlying
+// writer (or spool file).
+func (c *PrimaryMetadataWriter) Write(p *PackageEntry) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "package"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "rpm"}},
+	}
+
+	if err := c.encoder.EncodeElement(p, start); err != nil {
+		return fmt.Errorf("Error encoding package entry: %v", err)
+	}
+
+	c.count++
+	return nil
+}
+
+// Close finalizes primary.xml: the packages="N" attribute is patched (or
+// written, for non-seekable writers) to reflect the true number of
+// packages written, the root element is closed, and any spool file or
+// wrapped gzip.Writer is cleaned up.
+func (c *PrimaryMetadataWriter) Close() error {
+	if err := c.encoder.Flush(); err != nil {
+		return err
+	}
+
+	if c.seeker != nil {
+		if _, err := c.seeker.Seek(c.headerOffset, io.SeekStart); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(c.w, "%0*d", packagesCountWidth, c.count); err != nil {
+			return err
+		}
+
+		if _, err := c.seeker.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	} else {
+		defer os.Remove(c.spool.Name())
+		defer c.spool.Close()
+
+		if _, err := c.spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		header := xml.Header + fmt.Sprintf(`<metadata %s packages="%d">`+"\n", primaryXMLNS, c.count)
+		if _, err := io.WriteString(c.w, header); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(c.w, c.spool); err != nil {
+			return fmt.Errorf("Error copying spooled primary.xml body: %v", err)
+		}
+	}
+
+	if _, err := io.WriteString(c.w, "</metadata>\n"); err != nil {
+		return err
+	}
+
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package yum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// RepoMDFileType identifies one of the metadata files that a yum repository
+// advertises through repomd.xml.
+type RepoMDFileType string
+
+// The metadata file types understood by dnf/createrepo that go-yum is able
+// to produce.
+const (
+	RepoMDTypePrimary     RepoMDFileType = "primary"
+	RepoMDTypeFilelists   RepoMDFileType = "filelists"
+	RepoMDTypeOther       RepoMDFileType = "other"
+	RepoMDTypePrimaryDB   RepoMDFileType = "primary_db"
+	RepoMDTypeFilelistsDB RepoMDFileType = "filelists_db"
+	RepoMDTypeOtherDB     RepoMDFileType = "other_db"
+)
+
+// RepoMD represents the repomd.xml document that indexes every metadata
+// file in a yum repository.
+type RepoMD struct {
+	XMLName  xml.Name     `xml:"repomd"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	XMLNSRPM string       `xml:"xmlns:rpm,attr"`
+	Revision int64        `xml:"revision"`
+	Data     []RepoMDData `xml:"data"`
+
+	dir string
+}
+
+// RepoMDData describes a single metadata file registered in repomd.xml,
+// along with the checksums and sizes a client needs to validate it before
+// and after decompression.
+type RepoMDData struct {
+	Type         RepoMDFileType `xml:"type,attr"`
+	Checksum     RepoMDChecksum `xml:"checksum"`
+	OpenChecksum RepoMDChecksum `xml:"open-checksum"`
+	Location     RepoMDLocation `xml:"location"`
+	Timestamp    int64          `xml:"timestamp"`
+	Size         int64          `xml:"size"`
+	OpenSize     int64          `xml:"open-size"`
+}
+
+// RepoMDChecksum is the XML element used by both <checksum> and
+// <open-checksum> in repomd.xml.
+type RepoMDChecksum struct {
+	Type string `xml:"type,attr"`
+	Hash string `xml:",chardata"`
+}
+
+// RepoMDLocation is the location of a metadata file, relative to the
+// repository root.
+type RepoMDLocation struct {
+	Href string `xml:"href,attr"`
+}
+
+// NewRepoMD assembles a RepoMD describing the given metadata files. files
+// maps each file type to the path of the (already written, and optionally
+// gzip-compressed) file on disk inside targetDir, the repository's
+// repodata directory. The resulting repomd.xml is written to
+// targetDir/repomd.xml, and each location href is relative to the parent
+// of targetDir, the repository root.
+func NewRepoMD(targetDir string, files map[RepoMDFileType]string) (*RepoMD, error) {
+	md := &RepoMD{
+		XMLNS:    "http://linux.duke.edu/metadata/repo",
+		XMLNSRPM: "http://linux.duke.edu/metadata/rpm",
+		Revision: time.Now().Unix(),
+		dir:      targetDir,
+	}
+
+	for typ, path := range files {
+		data, err := newRepoMDData(typ, targetDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("Error describing %s metadata: %v", typ, err)
+		}
+
+		md.Data = append(md.Data, *data)
+	}
+
+	if err := md.write(); err != nil {
+		return nil, err
+	}
+
+	return md, nil
+}
+
+func newRepoMDData(typ RepoMDFileType, targetDir, path string) (*RepoMDData, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, size, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	openChecksum, openSize := checksum, size
+	if filepath.Ext(path) == ".gz" {
+		openChecksum, openSize, err = hashGzippedFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	href, err := filepath.Rel(filepath.Dir(filepath.Clean(targetDir)), path)
+	if err != nil {
+		href = filepath.Join(filepath.Base(filepath.Clean(targetDir)), filepath.Base(path))
+	}
+
+	return &RepoMDData{
+		Type:         typ,
+		Checksum:     RepoMDChecksum{Type: "sha256", Hash: checksum},
+		OpenChecksum: RepoMDChecksum{Type: "sha256", Hash: openChecksum},
+		Location:     RepoMDLocation{Href: filepath.ToSlash(href)},
+		Timestamp:    info.ModTime().Unix(),
+		Size:         size,
+		OpenSize:     openSize,
+	}, nil
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func hashGzippedFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", 0, err
+	}
+	defer gz.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, gz)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// write renders the repomd.xml document to targetDir/repomd.xml.
+func (c *RepoMD) write() error {
+	buf, err := xml.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error marshaling repomd.xml: %v", err)
+	}
+
+	out := append([]byte(xml.Header), buf...)
+	return ioutil.WriteFile(filepath.Join(c.dir, "repomd.xml"), out, 0644)
+}
+
+// SignRepoMD produces a detached, ASCII-armored signature of repomd.xml
+// using privKey, and writes it to repomd.xml.asc alongside it. This is what
+// allows a client configured with repo_gpgcheck=1 to trust the repository's
+// metadata without an external signing tool.
+func (c *RepoMD) SignRepoMD(privKey *openpgp.Entity) error {
+	in, err := os.Open(filepath.Join(c.dir, "repomd.xml"))
+	if err != nil {
+		return fmt.Errorf("Error opening repomd.xml: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(filepath.Join(c.dir, "repomd.xml.asc"))
+	if err != nil {
+		return fmt.Errorf("Error creating repomd.xml.asc: %v", err)
+	}
+	defer out.Close()
+
+	if err := openpgp.ArmoredDetachSign(out, privKey, in, nil); err != nil {
+		return fmt.Errorf("Error signing repomd.xml: %v", err)
+	}
+
+	return nil
+}
+
+// WritePublicKey emits the public half of privKey in armored form, suitable
+// for publishing as repomd.xml.key so clients can import it with
+// `rpm --import`.
+func WritePublicKey(privKey *openpgp.Entity, w io.Writer) error {
+	armored, err := armor.Encode(w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return fmt.Errorf("Error armoring public key: %v", err)
+	}
+
+	if err := privKey.Serialize(armored); err != nil {
+		return fmt.Errorf("Error serializing public key: %v", err)
+	}
+
+	return armored.Close()
+}
+
+// GenerateKeyPair creates a new OpenPGP key pair for signing repository
+// metadata, returning the armored private and public keys so a caller can
+// persist and reuse the repository's signing identity across rebuilds.
+func GenerateKeyPair(name, email string) (priv string, pub string, err error) {
+	entity, err := openpgp.NewEntity(name, "", email, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("Error generating key pair: %v", err)
+	}
+
+	var privBuf, pubBuf bytes.Buffer
+
+	privArmor, err := armor.Encode(&privBuf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("Error armoring private key: %v", err)
+	}
+	if err := entity.SerializePrivate(privArmor, nil); err != nil {
+		return "", "", fmt.Errorf("Error serializing private key: %v", err)
+	}
+	if err := privArmor.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := WritePublicKey(entity, &pubBuf); err != nil {
+		return "", "", err
+	}
+
+	return privBuf.String(), pubBuf.String(), nil
+}
@@ -0,0 +1,162 @@
+package yum
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cavaliercoder/go-rpm"
+)
+
+// RepoWriter assembles a complete createrepo-compatible metadata set
+// (primary, filelists and other, each as XML and as a SQLite database) for
+// a yum repository, one package at a time.
+type RepoWriter struct {
+	PrimaryDB   *PrimaryDatabase
+	FilelistsDB *FilelistsDatabase
+	OtherDB     *OtherDatabase
+
+	repodataDir string
+	primary     *PrimaryMetadata
+	filelists   *FilelistsMetadata
+	other       *OtherMetadata
+}
+
+// NewRepoWriter creates the primary_db, filelists_db and other_db SQLite
+// databases inside dir/repodata, and returns a RepoWriter ready to accept
+// packages via InsertPackage.
+func NewRepoWriter(dir string) (*RepoWriter, error) {
+	repodataDir := filepath.Join(dir, "repodata")
+	if err := os.MkdirAll(repodataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	primaryDB, err := CreatePrimaryDB(filepath.Join(repodataDir, "primary.sqlite"))
+	if err != nil {
+		return nil, err
+	}
+
+	filelistsDB, err := CreateFilelistsDB(filepath.Join(repodataDir, "filelists.sqlite"))
+	if err != nil {
+		return nil, err
+	}
+
+	otherDB, err := CreateOtherDB(filepath.Join(repodataDir, "other.sqlite"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RepoWriter{
+		PrimaryDB:   primaryDB,
+		FilelistsDB: filelistsDB,
+		OtherDB:     otherDB,
+		repodataDir: repodataDir,
+		primary:     &PrimaryMetadata{Packages: make(PackageEntries, 0)},
+		filelists:   &FilelistsMetadata{Packages: make([]FilelistsPackage, 0)},
+		other:       &OtherMetadata{Packages: make([]OtherPackage, 0)},
+	}, nil
+}
+
+// InsertPackage populates all three databases and their matching XML
+// documents (primary, filelists, other) from a single *rpm.PackageFile.
+func (c *RepoWriter) InsertPackage(p *rpm.PackageFile) error {
+	if err := c.PrimaryDB.InsertPackage(p); err != nil {
+		return err
+	}
+
+	if err := c.FilelistsDB.InsertPackage(p); err != nil {
+		return err
+	}
+
+	if err := c.OtherDB.InsertPackage(p); err != nil {
+		return err
+	}
+
+	sum, err := p.Checksum()
+	if err != nil {
+		return err
+	}
+
+	c.primary.Packages = append(c.primary.Packages, PackageEntry{
+		Arch:        p.Architecture(),
+		Size:        PackageEntrySize{Package: p.FileSize(), Installed: p.Size(), Archive: p.ArchiveSize()},
+		Checksums:   PackageEntryChecksum{Type: p.ChecksumType(), Pkgid: "YES", Hash: sum},
+		Location:    PackageEntryLocation{Href: filepath.Base(p.Path())},
+		PackageName: p.Name(),
+		Versions:    PackageEntryVersion{Epoch: p.Epoch(), Version: p.Version(), Release: p.Release()},
+		Time:        PackageEntryTime{File: p.FileTime().Unix(), Build: p.BuildTime().Unix()},
+		Summary:     p.Summary(),
+		Url:         p.URL(),
+		Packager:    p.Packager(),
+	})
+
+	files := make([]FilelistsFile, 0, len(p.Files()))
+	for _, f := range p.Files() {
+		files = append(files, FilelistsFile{Path: f})
+	}
+
+	c.filelists.Packages = append(c.filelists.Packages, FilelistsPackage{
+		PkgId:   sum,
+		Name:    p.Name(),
+		Arch:    p.Architecture(),
+		Version: FilelistsVersion{Epoch: p.Epoch(), Version: p.Version(), Release: p.Release()},
+		Files:   files,
+	})
+
+	changelogs := make([]OtherChangelog, 0, len(p.Changelogs()))
+	for _, entry := range p.Changelogs() {
+		changelogs = append(changelogs, OtherChangelog{Author: entry.Name, Date: entry.Time, Text: entry.Text})
+	}
+
+	c.other.Packages = append(c.other.Packages, OtherPackage{
+		PkgId:      sum,
+		Name:       p.Name(),
+		Arch:       p.Architecture(),
+		Version:    OtherVersion{Epoch: p.Epoch(), Version: p.Version(), Release: p.Release()},
+		Changelogs: changelogs,
+	})
+
+	return nil
+}
+
+// Close writes primary.xml, filelists.xml and other.xml to the repodata
+// directory and closes the underlying databases.
+func (c *RepoWriter) Close() error {
+	if err := writeMetadataFile(filepath.Join(c.repodataDir, "primary.xml"), func(w io.Writer) error {
+		return WritePrimaryMetadata(w, c.primary)
+	}); err != nil {
+		return err
+	}
+
+	if err := writeMetadataFile(filepath.Join(c.repodataDir, "filelists.xml"), func(w io.Writer) error {
+		return WriteFilelistsMetadata(w, c.filelists)
+	}); err != nil {
+		return err
+	}
+
+	if err := writeMetadataFile(filepath.Join(c.repodataDir, "other.xml"), func(w io.Writer) error {
+		return WriteOtherMetadata(w, c.other)
+	}); err != nil {
+		return err
+	}
+
+	if err := c.PrimaryDB.Close(); err != nil {
+		return err
+	}
+
+	if err := c.FilelistsDB.Close(); err != nil {
+		return err
+	}
+
+	return c.OtherDB.Close()
+}
+
+func writeMetadataFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return write(f)
+}